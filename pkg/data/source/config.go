@@ -0,0 +1,42 @@
+package source
+
+// DataSourceType identifies how a target should obtain the points it loads.
+type DataSourceType string
+
+const (
+	FileDataSourceType      DataSourceType = "FILE"
+	SimulatorDataSourceType DataSourceType = "SIMULATOR"
+	// LineProtocolDataSourceType reads points that were captured in InfluxDB
+	// line-protocol form, e.g. from a recorded `influxd` write stream,
+	// instead of the TSBS binary format or the built-in simulator.
+	LineProtocolDataSourceType DataSourceType = "LINE_PROTOCOL"
+)
+
+// DataSourceConfig selects and configures exactly one of the DataSourceTypes
+// above for a benchmark run.
+type DataSourceConfig struct {
+	Type DataSourceType
+
+	File         *FileDataSourceConfig
+	Simulator    *SimulatorDataSourceConfig
+	LineProtocol *LineProtocolDataSourceConfig
+}
+
+// FileDataSourceConfig points at a pre-generated data file on disk.
+type FileDataSourceConfig struct {
+	Location string
+}
+
+// SimulatorDataSourceConfig configures the in-process TSBS data generator.
+type SimulatorDataSourceConfig struct {
+	// left to the usecase-specific simulator builder; populated by the
+	// top-level config loader.
+}
+
+// LineProtocolDataSourceConfig points at a captured InfluxDB line-protocol
+// file. SchemaFile is optional: when empty, the data source derives its
+// tag/field headers by scanning the file once before replaying it.
+type LineProtocolDataSourceConfig struct {
+	Location   string
+	SchemaFile string
+}
@@ -0,0 +1,44 @@
+package timestream
+
+import (
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// batch accumulates the deserializedPoints appended to it since it was last
+// reset, in the order they were appended.
+type batch struct {
+	points []*deserializedPoint
+}
+
+func (b *batch) Len() int {
+	return len(b.points)
+}
+
+func (b *batch) Append(item data.LoadedPoint) {
+	b.points = append(b.points, item.Data.(*deserializedPoint))
+}
+
+func (b *batch) reset() {
+	b.points = b.points[:0]
+}
+
+// batchFactory hands out and recycles *batch values through a sync.Pool so
+// a long-running load doesn't keep reallocating point slices every flush.
+type batchFactory struct {
+	pool *sync.Pool
+}
+
+func NewBatchFactory() *batchFactory {
+	return &batchFactory{
+		pool: &sync.Pool{
+			New: func() interface{} { return &batch{} },
+		},
+	}
+}
+
+func (f *batchFactory) New() targets.Batch {
+	return f.pool.Get().(*batch)
+}
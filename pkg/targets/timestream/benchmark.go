@@ -2,7 +2,6 @@ package timestream
 
 import (
 	"bufio"
-	"fmt"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/timestreamwrite"
 	"github.com/pkg/errors"
@@ -21,6 +20,7 @@ type benchmark struct {
 	targetDb     string
 	batchFactory *batchFactory
 	awsSession   *session.Session
+	rotation     *RotationRouter
 }
 
 func newBenchmark(targetDb string, config *SpecificConfig, dataSourceConfig *source.DataSourceConfig) (targets.Benchmark, error) {
@@ -32,12 +32,22 @@ func newBenchmark(targetDb string, config *SpecificConfig, dataSourceConfig *sou
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create data source")
 	}
+
+	var rotation *RotationRouter
+	if config.Rotation != "" {
+		rotation, err = NewRotationRouter(tableName, config.Rotation, config.RotationCount)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create rotation router")
+		}
+	}
+
 	return &benchmark{
 		config:       config,
 		ds:           ds,
 		batchFactory: NewBatchFactory(),
 		awsSession:   awsSession,
 		targetDb:     targetDb,
+		rotation:     rotation,
 	}, nil
 }
 
@@ -50,29 +60,63 @@ func (b benchmark) GetBatchFactory() targets.BatchFactory {
 }
 
 func (b benchmark) GetPointIndexer(maxPartitions uint) targets.PointIndexer {
-	hashProvider, err := createHashProvider(b.ds, b.config.HashProperty)
+	hashProvider, err := createHashProvider(b.ds, b.config.HashProperty, b.config.HashStrategy)
 	if err != nil {
 		log.Fatalf("could not create point indexer: %v", err)
 		return nil
 	}
+	if b.rotation != nil {
+		hashProvider = withRotationSegment(hashProvider, b.rotation)
+	}
 	return common.NewGenericPointIndexer(maxPartitions, hashProvider)
 }
 
+// withRotationSegment folds a point's rotation segment into the hash key a
+// hashProvider produces, so that points with the same dimensions but in
+// different rotation windows get different keys and can be spread over
+// different partitions. The segment is folded in as a single byte, so
+// segments 256 apart add the same byte.
+func withRotationSegment(hashProvider func(point *data.LoadedPoint) []byte, rotation *RotationRouter) func(point *data.LoadedPoint) []byte {
+	return func(point *data.LoadedPoint) []byte {
+		dp := point.Data.(*deserializedPoint)
+		key := hashProvider(point)
+		return append(key, byte(rotation.SegmentFor(dp.timestamp)))
+	}
+}
+
 func (b benchmark) GetProcessor() targets.Processor {
+	writeService, err := newRetryingWriter(timestreamwrite.New(b.awsSession), b.config)
+	if err != nil {
+		log.Fatalf("could not create timestream writer: %v", err)
+	}
+
+	if b.config.RecordMode == MultiMeasureRecordMode {
+		return &multiMeasureProcessor{
+			dbName:              b.targetDb,
+			batchPool:           b.batchFactory.pool,
+			headers:             b.ds.Headers(),
+			writeService:        writeService,
+			useCommonAttributes: b.config.UseCommonAttributes,
+			rotation:            b.rotation,
+		}
+	}
+
 	if b.config.UseCommonAttributes {
 		return &commonDimensionsProcessor{
 			dbName:       b.targetDb,
 			batchPool:    b.batchFactory.pool,
 			headers:      b.ds.Headers(),
-			writeService: timestreamwrite.New(b.awsSession),
+			writeService: writeService,
+			rotation:     b.rotation,
 		}
 	}
 
 	return &eachValueARecordProcessor{
 		batchPool:    b.batchFactory.pool,
-		writeService: timestreamwrite.New(b.awsSession),
+		writeService: writeService,
 		headers:      b.ds.Headers(),
 		dbName:       b.targetDb,
+		rotation:     b.rotation,
 	}
 }
 
@@ -82,14 +126,21 @@ func (b benchmark) GetDBCreator() targets.DBCreator {
 		writeSvc:                           timestreamwrite.New(b.awsSession),
 		magneticStoreRetentionPeriodInDays: b.config.MagStoreRetentionInDays,
 		memoryRetentionPeriodInHours:       b.config.MemStoreRetentionInHours,
+		rotation:                           b.rotation,
 	}
 }
 
 func initDataSource(config *source.DataSourceConfig, useCurrentTs bool) (targets.DataSource, error) {
 	if config.Type == source.FileDataSourceType {
 		br := load.GetBufferedReader(config.File.Location)
+		scanner := bufio.NewScanner(br)
+		headers, err := readTSBSBinaryHeaders(scanner)
+		if err != nil {
+			return nil, err
+		}
 		return &fileDataSource{
-			scanner:      bufio.NewScanner(br),
+			scanner:      scanner,
+			headers:      headers,
 			useCurrentTs: useCurrentTs,
 		}, nil
 	} else if config.Type == source.SimulatorDataSourceType {
@@ -102,29 +153,8 @@ func initDataSource(config *source.DataSourceConfig, useCurrentTs bool) (targets
 			simulator:    simulator,
 			useCurrentTs: useCurrentTs,
 		}, nil
+	} else if config.Type == source.LineProtocolDataSourceType {
+		return newLineProtocolDataSource(config.LineProtocol, useCurrentTs)
 	}
 	panic("unhandled data source type!!!")
 }
-
-// createHashProvider creates the function that will take out the
-// value used to calculate the hash depending on which is the
-// hashProperty.
-func createHashProvider(ds targets.DataSource, hashTag string) (func(point *data.LoadedPoint) []byte, error) {
-	headers := ds.Headers()
-	tagIndex := -1
-	for i, tagKey := range headers.TagKeys {
-		if tagKey == hashTag {
-			tagIndex = i
-			break
-		}
-	}
-	if tagIndex < 0 {
-		return nil, fmt.Errorf("no dimension named '%s' found in data points", hashTag)
-	}
-
-	return func(point *data.LoadedPoint) []byte {
-		var dp deserializedPoint
-		dp = *point.Data.(*deserializedPoint)
-		return []byte(dp.tags[tagIndex])
-	}, nil
-}
@@ -0,0 +1,76 @@
+package timestream
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// SpecificConfig holds all the non-generic settings that are needed to
+// write to or read from a Timestream database as part of a benchmark run.
+type SpecificConfig struct {
+	AwsRegion   string `mapstructure:"aws-region"`
+	AwsProfile  string `mapstructure:"aws-profile"`
+	AwsEndpoint string `mapstructure:"aws-endpoint"`
+
+	// HashProperty is a comma-separated list of dimension (tag) names to
+	// partition writer goroutines by; multiple names are combined into a
+	// composite key. See HashStrategy for the algorithm used to hash it.
+	HashProperty string `mapstructure:"hash-property"`
+	// HashStrategy selects the hash algorithm createHashProvider uses:
+	// "fnv" (the default, cheapest), "xxhash", or "murmur3" (best
+	// distribution, more CPU per point).
+	HashStrategy string `mapstructure:"hash-strategy"`
+
+	UseCommonAttributes      bool  `mapstructure:"use-common-attributes"`
+	UseCurrentTime           bool  `mapstructure:"use-current-time"`
+	MagStoreRetentionInDays  int64 `mapstructure:"magnetic-store-retention-in-days"`
+	MemStoreRetentionInHours int64 `mapstructure:"memory-store-retention-in-hours"`
+
+	// RecordMode selects how a point's fields are packed into Timestream
+	// Records: "" (the default) writes one Record per field, "multi" packs
+	// every field of a point into a single MULTI-measure-value Record.
+	RecordMode string `mapstructure:"record-mode"`
+
+	// MaxRetries, InitialBackoff and MaxBackoff configure retryingWriter's
+	// exponential backoff for throttling/5xx errors; zero values fall back
+	// to its built-in defaults. DeadLetterPath, if set, is where records
+	// that still can't be written end up instead of aborting the run.
+	MaxRetries     int           `mapstructure:"max-retries"`
+	InitialBackoff time.Duration `mapstructure:"initial-backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max-backoff"`
+	DeadLetterPath string        `mapstructure:"dead-letter-path"`
+
+	// Rotation, if set to "daily", "weekly" or "monthly", pre-creates a ring
+	// of RotationCount tables and routes each point to the one whose window
+	// contains its timestamp instead of writing everything to one table.
+	Rotation      string `mapstructure:"rotation"`
+	RotationCount int    `mapstructure:"rotation-count"`
+}
+
+// MultiMeasureRecordMode is the SpecificConfig.RecordMode value that routes
+// GetProcessor to the multiMeasureProcessor.
+const MultiMeasureRecordMode = "multi"
+
+// OpenAWSSession creates the AWS session that every Timestream client in
+// this target is built on top of, honoring the region/endpoint/profile
+// overrides supplied on the command line.
+func OpenAWSSession(config *SpecificConfig) (*session.Session, error) {
+	awsConfig := aws.NewConfig()
+	if config.AwsRegion != "" {
+		awsConfig = awsConfig.WithRegion(config.AwsRegion)
+	}
+	if config.AwsEndpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.AwsEndpoint)
+	}
+
+	opts := session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	}
+	if config.AwsProfile != "" {
+		opts.Profile = config.AwsProfile
+	}
+	return session.NewSessionWithOptions(opts)
+}
@@ -0,0 +1,116 @@
+package timestream
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+	"github.com/pkg/errors"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// dbCreator provisions the Timestream database and table(s) a benchmark run
+// writes into, ahead of the load starting.
+type dbCreator struct {
+	ds       targets.DataSource
+	writeSvc *timestreamwrite.TimestreamWrite
+
+	magneticStoreRetentionPeriodInDays int64
+	memoryRetentionPeriodInHours       int64
+
+	// rotation, when set, switches CreateDB from provisioning the single
+	// tableName table to pre-creating the whole rotation ring up front.
+	rotation *RotationRouter
+}
+
+func (d *dbCreator) Init() {}
+
+func (d *dbCreator) DBExists(dbName string) (bool, error) {
+	_, err := d.writeSvc.DescribeDatabase(&timestreamwrite.DescribeDatabaseInput{
+		DatabaseName: aws.String(dbName),
+	})
+	if err != nil {
+		if _, ok := err.(*timestreamwrite.ResourceNotFoundException); ok {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "could not describe timestream database")
+	}
+	return true, nil
+}
+
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	_, err := d.writeSvc.DeleteDatabase(&timestreamwrite.DeleteDatabaseInput{
+		DatabaseName: aws.String(dbName),
+	})
+	if err != nil {
+		if _, ok := err.(*timestreamwrite.ResourceNotFoundException); ok {
+			return nil
+		}
+		return errors.Wrap(err, "could not remove old timestream database")
+	}
+	return nil
+}
+
+// CreateDB provisions the database and its table(s). Timestream tables are
+// schemaless with respect to measures, so no separate declaration is needed
+// to accept MULTI-measure-value records: multiMeasureProcessor's writes are
+// simply accepted once RecordMode routes to it.
+func (d *dbCreator) CreateDB(dbName string) error {
+	_, err := d.writeSvc.CreateDatabase(&timestreamwrite.CreateDatabaseInput{
+		DatabaseName: aws.String(dbName),
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create timestream database")
+	}
+
+	if d.rotation != nil {
+		return d.createRotationRing(dbName)
+	}
+
+	_, err = d.writeSvc.CreateTable(&timestreamwrite.CreateTableInput{
+		DatabaseName: aws.String(dbName),
+		TableName:    aws.String(tableName),
+		RetentionProperties: &timestreamwrite.RetentionProperties{
+			MagneticStoreRetentionPeriodInDays: aws.Int64(d.magneticStoreRetentionPeriodInDays),
+			MemoryStoreRetentionPeriodInHours:  aws.Int64(d.memoryRetentionPeriodInHours),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create timestream table")
+	}
+	return nil
+}
+
+// createRotationRing pre-creates every table in the rotation ring so writes
+// never race a CreateTable call once the run starts. Segments further from
+// the current window get a shorter memory-store retention, since they hold
+// progressively older data that belongs in the (cheaper) magnetic store
+// sooner.
+func (d *dbCreator) createRotationRing(dbName string) error {
+	now := time.Now()
+	for segment, name := range d.rotation.TableNames() {
+		age := d.rotation.segmentAge(segment, now)
+		memHours := d.memoryRetentionPeriodInHours >> uint(age)
+		if memHours < 1 {
+			memHours = 1
+		}
+
+		_, err := d.writeSvc.CreateTable(&timestreamwrite.CreateTableInput{
+			DatabaseName: aws.String(dbName),
+			TableName:    aws.String(name),
+			RetentionProperties: &timestreamwrite.RetentionProperties{
+				MagneticStoreRetentionPeriodInDays: aws.Int64(d.magneticStoreRetentionPeriodInDays),
+				MemoryStoreRetentionPeriodInHours:  aws.Int64(memHours),
+			},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "could not create rotated timestream table %q", name)
+		}
+	}
+	return nil
+}
+
+// tableName is the table every point in a benchmark run is written to when
+// rotation is disabled; Timestream databases in this target otherwise hold
+// exactly one table.
+const tableName = "measurements"
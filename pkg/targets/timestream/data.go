@@ -0,0 +1,152 @@
+package timestream
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/usecases/common"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// deserializedPoint is the format-agnostic representation every DataSource in
+// this target produces, regardless of how the point was encoded on disk.
+type deserializedPoint struct {
+	measurementName string
+	tags            []string
+	fields          []interface{}
+	timestamp       int64
+}
+
+// fileDataSource replays points that were captured to disk ahead of time. The
+// TSBS-generated binary format is the default; see line_protocol.go for the
+// InfluxDB line-protocol variant.
+type fileDataSource struct {
+	scanner      *bufio.Scanner
+	headers      *targets.DataSourceHeaders
+	useCurrentTs bool
+}
+
+func (d *fileDataSource) Headers() *targets.DataSourceHeaders {
+	return d.headers
+}
+
+// readTSBSBinaryHeaders consumes the single preamble line TSBS-generated
+// data files start with -- "tagKey1,tagKey2,...|fieldKey1,fieldKey2,..." --
+// and leaves the scanner positioned at the first data point.
+func readTSBSBinaryHeaders(scanner *bufio.Scanner) (*targets.DataSourceHeaders, error) {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty data file: missing header line")
+	}
+	parts := strings.SplitN(scanner.Text(), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed header line %q", scanner.Text())
+	}
+	return &targets.DataSourceHeaders{
+		TagKeys:   strings.Split(parts[0], ","),
+		FieldKeys: strings.Split(parts[1], ","),
+	}, nil
+}
+
+func (d *fileDataSource) NextItem() data.LoadedPoint {
+	if !d.scanner.Scan() {
+		if err := d.scanner.Err(); err != nil {
+			log.Fatalf("timestream fileDataSource: scan error: %v", err)
+		}
+		return data.LoadedPoint{}
+	}
+	point, err := decodeTSBSBinaryLine(d.scanner.Text(), d.headers, d.useCurrentTs)
+	if err != nil {
+		log.Fatalf("timestream fileDataSource: %v", err)
+	}
+	return data.LoadedPoint{Data: point}
+}
+
+// decodeTSBSBinaryLine decodes a single line of the native TSBS point format:
+// measurement,tag1,tag2,...,field1,field2,...,timestamp with tag/field values
+// aligned positionally to the headers derived from the file preamble.
+func decodeTSBSBinaryLine(line string, headers *targets.DataSourceHeaders, useCurrentTs bool) (*deserializedPoint, error) {
+	cols := strings.Split(line, ",")
+	tagCount := len(headers.TagKeys)
+	fieldCount := len(headers.FieldKeys)
+
+	p := &deserializedPoint{
+		measurementName: cols[0],
+		tags:            cols[1 : 1+tagCount],
+		fields:          make([]interface{}, fieldCount),
+	}
+	for i, raw := range cols[1+tagCount : 1+tagCount+fieldCount] {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		p.fields[i] = v
+	}
+	if useCurrentTs {
+		p.timestamp = time.Now().UnixNano()
+	} else if len(cols) > 1+tagCount+fieldCount {
+		ts, err := strconv.ParseInt(cols[1+tagCount+fieldCount], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		p.timestamp = ts
+	}
+	return p, nil
+}
+
+// simulatorDataSource generates points on the fly via the shared TSBS data
+// generator instead of replaying a captured file.
+type simulatorDataSource struct {
+	simulator    common.Simulator
+	headers      *targets.DataSourceHeaders
+	useCurrentTs bool
+}
+
+func (d *simulatorDataSource) Headers() *targets.DataSourceHeaders {
+	if d.headers == nil {
+		d.headers = simulatorHeaders(d.simulator)
+	}
+	return d.headers
+}
+
+func (d *simulatorDataSource) NextItem() data.LoadedPoint {
+	if d.simulator.Finished() {
+		return data.LoadedPoint{}
+	}
+	var simPoint data.Point
+	d.simulator.Next(&simPoint)
+
+	p := &deserializedPoint{
+		measurementName: simPoint.MeasurementName(),
+		tags:            simPoint.TagValues(),
+		fields:          simPoint.FieldValues(),
+	}
+	if d.useCurrentTs {
+		p.timestamp = time.Now().UnixNano()
+	} else {
+		p.timestamp = simPoint.TimestampInUnixNs()
+	}
+	return data.LoadedPoint{Data: p}
+}
+
+// simulatorHeaders flattens the simulator's per-measurement schema into the
+// single tag/field list this target works with; Timestream tables hold one
+// measurement per benchmark run.
+func simulatorHeaders(sim common.Simulator) *targets.DataSourceHeaders {
+	var fieldKeys []string
+	for _, keys := range sim.Fields() {
+		fieldKeys = keys
+		break
+	}
+	return &targets.DataSourceHeaders{
+		TagKeys:   sim.TagKeys(),
+		FieldKeys: fieldKeys,
+	}
+}
@@ -0,0 +1,93 @@
+package timestream
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spaolacci/murmur3"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// hashKeySeparator joins the values of a composite hash key. A NUL byte is
+// used since it cannot occur in a tag value, so e.g. ("ab", "c") and ("a",
+// "bc") never collide.
+const hashKeySeparator = "\x00"
+
+// createHashProvider builds the function common.NewGenericPointIndexer uses
+// to assign a point to a writer goroutine. hashProperty is a comma-separated
+// list of dimension names; their values are concatenated with
+// hashKeySeparator into a composite key before being hashed with
+// hashStrategy (empty defaults to "fnv"). All dimension indexes are resolved
+// once here so the per-point hot path in the returned function does no
+// dimension lookups. It is not allocation-free: a composite key is built in
+// a fresh buffer, and every hash strategy returns a newly allocated digest.
+func createHashProvider(ds targets.DataSource, hashProperty, hashStrategy string) (func(point *data.LoadedPoint) []byte, error) {
+	headers := ds.Headers()
+	dimensions := strings.Split(hashProperty, ",")
+	tagIndexes := make([]int, len(dimensions))
+	for i, dim := range dimensions {
+		dim = strings.TrimSpace(dim)
+		tagIndexes[i] = -1
+		for j, tagKey := range headers.TagKeys {
+			if tagKey == dim {
+				tagIndexes[i] = j
+				break
+			}
+		}
+		if tagIndexes[i] < 0 {
+			return nil, fmt.Errorf("no dimension named '%s' found in data points", dim)
+		}
+	}
+
+	hashFn, err := hashFunc(hashStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(point *data.LoadedPoint) []byte {
+		dp := point.Data.(*deserializedPoint)
+		if len(tagIndexes) == 1 {
+			return hashFn(dp.tags[tagIndexes[0]])
+		}
+
+		var key strings.Builder
+		for i, tagIndex := range tagIndexes {
+			if i > 0 {
+				key.WriteString(hashKeySeparator)
+			}
+			key.WriteString(dp.tags[tagIndex])
+		}
+		return hashFn(key.String())
+	}, nil
+}
+
+// hashFunc resolves a HashStrategy name to the function used to digest a
+// composite key into the bytes common.NewGenericPointIndexer partitions on.
+func hashFunc(strategy string) (func(string) []byte, error) {
+	switch strategy {
+	case "", "fnv":
+		return func(s string) []byte {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(s))
+			return h.Sum(nil)
+		}, nil
+	case "xxhash":
+		return func(s string) []byte {
+			h := xxhash.Sum64String(s)
+			return []byte{
+				byte(h >> 56), byte(h >> 48), byte(h >> 40), byte(h >> 32),
+				byte(h >> 24), byte(h >> 16), byte(h >> 8), byte(h),
+			}
+		}, nil
+	case "murmur3":
+		return func(s string) []byte {
+			h := murmur3.Sum32([]byte(s))
+			return []byte{byte(h >> 24), byte(h >> 16), byte(h >> 8), byte(h)}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown hash strategy '%s'", strategy)
+	}
+}
@@ -0,0 +1,98 @@
+package timestream
+
+import (
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// fakeDataSource is a minimal targets.DataSource stand-in so
+// createHashProvider can resolve dimension names against a fixed header set
+// without needing a real file or simulator behind it.
+type fakeDataSource struct {
+	headers *targets.DataSourceHeaders
+}
+
+func (f *fakeDataSource) Headers() *targets.DataSourceHeaders { return f.headers }
+func (f *fakeDataSource) NextItem() data.LoadedPoint          { return data.LoadedPoint{} }
+
+func newFakeDataSource(tagKeys ...string) *fakeDataSource {
+	return &fakeDataSource{headers: &targets.DataSourceHeaders{TagKeys: tagKeys}}
+}
+
+func loadedPoint(tags ...string) *data.LoadedPoint {
+	return &data.LoadedPoint{Data: &deserializedPoint{tags: tags}}
+}
+
+func TestCreateHashProviderSingleDimension(t *testing.T) {
+	ds := newFakeDataSource("hostname", "region")
+	hash, err := createHashProvider(ds, "hostname", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := hash(loadedPoint("host-1", "us-west"))
+	b := hash(loadedPoint("host-1", "us-east"))
+	c := hash(loadedPoint("host-2", "us-west"))
+
+	if string(a) != string(b) {
+		t.Errorf("hash should only depend on hostname: %x != %x", a, b)
+	}
+	if string(a) == string(c) {
+		t.Errorf("different hostnames hashed to the same key: %x", a)
+	}
+}
+
+func TestCreateHashProviderCompositeDimensions(t *testing.T) {
+	ds := newFakeDataSource("hostname", "region")
+	hash, err := createHashProvider(ds, "hostname,region", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	same := hash(loadedPoint("host-1", "us-west"))
+	again := hash(loadedPoint("host-1", "us-west"))
+	if string(same) != string(again) {
+		t.Errorf("hash of identical composite key should be stable")
+	}
+
+	other := hash(loadedPoint("host-1", "us-east"))
+	if string(same) == string(other) {
+		t.Errorf("different composite keys hashed to the same value: %x", same)
+	}
+}
+
+func TestCreateHashProviderUnknownDimension(t *testing.T) {
+	ds := newFakeDataSource("hostname")
+	if _, err := createHashProvider(ds, "does-not-exist", ""); err == nil {
+		t.Fatal("expected error for unknown dimension, got nil")
+	}
+}
+
+func TestHashFuncStrategies(t *testing.T) {
+	for _, strategy := range []string{"", "fnv", "xxhash", "murmur3"} {
+		hashFn, err := hashFunc(strategy)
+		if err != nil {
+			t.Fatalf("hashFunc(%q): unexpected error: %v", strategy, err)
+		}
+		a := hashFn("foo")
+		b := hashFn("foo")
+		c := hashFn("bar")
+		if len(a) == 0 {
+			t.Errorf("hashFunc(%q): empty digest", strategy)
+		}
+		if string(a) != string(b) {
+			t.Errorf("hashFunc(%q): not deterministic: %x != %x", strategy, a, b)
+		}
+		if string(a) == string(c) {
+			t.Errorf("hashFunc(%q): distinct inputs collided: %x", strategy, a)
+		}
+	}
+}
+
+func TestHashFuncUnknownStrategy(t *testing.T) {
+	if _, err := hashFunc("not-a-real-strategy"); err == nil {
+		t.Fatal("expected error for unknown hash strategy, got nil")
+	}
+}
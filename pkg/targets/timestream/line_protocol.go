@@ -0,0 +1,335 @@
+package timestream
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/source"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// lineProtocolDataSource replays InfluxDB line-protocol points, e.g. a
+// capture of real `influxd` write traffic, so it can be loaded into
+// Timestream without re-running a data generator. Unlike fileDataSource it
+// cannot assume a fixed header: the tag/field set can vary line to line, so
+// the available keys are resolved either from a supplied schema file or a
+// preflight scan of the input before any point is handed out.
+type lineProtocolDataSource struct {
+	scanner      *bufio.Scanner
+	headers      *targets.DataSourceHeaders
+	tagIndex     map[string]int
+	fieldIndex   map[string]int
+	useCurrentTs bool
+}
+
+func newLineProtocolDataSource(config *source.LineProtocolDataSourceConfig, useCurrentTs bool) (*lineProtocolDataSource, error) {
+	var headers *targets.DataSourceHeaders
+	var err error
+	if config.SchemaFile != "" {
+		headers, err = readLineProtocolSchemaFile(config.SchemaFile)
+	} else {
+		headers, err = scanLineProtocolHeaders(config.Location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	br := load.GetBufferedReader(config.Location)
+	return &lineProtocolDataSource{
+		scanner:      bufio.NewScanner(br),
+		headers:      headers,
+		tagIndex:     indexOf(headers.TagKeys),
+		fieldIndex:   indexOf(headers.FieldKeys),
+		useCurrentTs: useCurrentTs,
+	}, nil
+}
+
+func indexOf(keys []string) map[string]int {
+	idx := make(map[string]int, len(keys))
+	for i, k := range keys {
+		idx[k] = i
+	}
+	return idx
+}
+
+func (d *lineProtocolDataSource) Headers() *targets.DataSourceHeaders {
+	return d.headers
+}
+
+func (d *lineProtocolDataSource) NextItem() data.LoadedPoint {
+	if !d.scanner.Scan() {
+		if err := d.scanner.Err(); err != nil {
+			log.Fatalf("timestream lineProtocolDataSource: scan error: %v", err)
+		}
+		return data.LoadedPoint{}
+	}
+	point, err := d.parseLine(d.scanner.Text())
+	if err != nil {
+		log.Fatalf("timestream lineProtocolDataSource: %v", err)
+	}
+	return data.LoadedPoint{Data: point}
+}
+
+func (d *lineProtocolDataSource) parseLine(line string) (*deserializedPoint, error) {
+	measurement, tagKVs, fieldKVs, ts, err := splitLineProtocol(line)
+	if err != nil {
+		return nil, fmt.Errorf("malformed line-protocol point %q: %w", line, err)
+	}
+
+	p := &deserializedPoint{
+		measurementName: measurement,
+		tags:            make([]string, len(d.headers.TagKeys)),
+		fields:          make([]interface{}, len(d.headers.FieldKeys)),
+	}
+	for _, kv := range tagKVs {
+		if i, ok := d.tagIndex[kv.key]; ok {
+			p.tags[i] = kv.value
+		}
+	}
+	for _, kv := range fieldKVs {
+		i, ok := d.fieldIndex[kv.key]
+		if !ok {
+			continue
+		}
+		v, err := decodeLineProtocolFieldValue(kv.value)
+		if err != nil {
+			return nil, err
+		}
+		p.fields[i] = v
+	}
+	if d.useCurrentTs {
+		p.timestamp = time.Now().UnixNano()
+	} else if ts != nil {
+		p.timestamp = *ts
+	}
+	return p, nil
+}
+
+// scanLineProtocolHeaders makes a single pass over the file to union every
+// tag key and field key it sees, so Headers() can be derived without
+// requiring operators to hand-write a schema.
+func scanLineProtocolHeaders(location string) (*targets.DataSourceHeaders, error) {
+	tagSeen := map[string]bool{}
+	fieldSeen := map[string]bool{}
+	headers := &targets.DataSourceHeaders{}
+
+	scanner := bufio.NewScanner(load.GetBufferedReader(location))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		_, tagKVs, fieldKVs, _, err := splitLineProtocol(line)
+		if err != nil {
+			return nil, fmt.Errorf("malformed line-protocol point %q: %w", line, err)
+		}
+		for _, kv := range tagKVs {
+			if !tagSeen[kv.key] {
+				tagSeen[kv.key] = true
+				headers.TagKeys = append(headers.TagKeys, kv.key)
+			}
+		}
+		for _, kv := range fieldKVs {
+			if !fieldSeen[kv.key] {
+				fieldSeen[kv.key] = true
+				headers.FieldKeys = append(headers.FieldKeys, kv.key)
+			}
+		}
+	}
+	return headers, scanner.Err()
+}
+
+// readLineProtocolSchemaFile lets operators pin the header set explicitly
+// (one "tag:<key>" or "field:<key>" line each) instead of paying for a
+// preflight scan over a very large capture.
+func readLineProtocolSchemaFile(location string) (*targets.DataSourceHeaders, error) {
+	headers := &targets.DataSourceHeaders{}
+	scanner := bufio.NewScanner(load.GetBufferedReader(location))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "tag:"):
+			headers.TagKeys = append(headers.TagKeys, strings.TrimPrefix(line, "tag:"))
+		case strings.HasPrefix(line, "field:"):
+			headers.FieldKeys = append(headers.FieldKeys, strings.TrimPrefix(line, "field:"))
+		}
+	}
+	return headers, scanner.Err()
+}
+
+type lpKV struct {
+	key   string
+	value string
+}
+
+// splitLineProtocol tokenizes a single line-protocol point into its
+// measurement, tag set, field set and optional nanosecond timestamp, per
+// https://docs.influxdata.com/influxdb/v1/write_protocols/line_protocol_reference/.
+// The measurement name, tag keys, tag values and field keys are returned
+// with their escaped commas/spaces/equals already unescaped; field values
+// are returned raw and unescaped afterwards in decodeLineProtocolFieldValue
+// since that is type-dependent (quoted strings unescape differently than
+// bare tokens).
+func splitLineProtocol(line string) (measurement string, tags []lpKV, fields []lpKV, ts *int64, err error) {
+	identEnd := unescapedIndexByte(line, ' ', false)
+	if identEnd < 0 {
+		return "", nil, nil, nil, fmt.Errorf("missing field set")
+	}
+	identTokens := splitUnescaped(line[:identEnd], ',', false)
+	measurement = unescapeLineProtocol(identTokens[0])
+	for _, tok := range identTokens[1:] {
+		k, v, err := splitKV(tok)
+		if err != nil {
+			return "", nil, nil, nil, err
+		}
+		tags = append(tags, lpKV{key: unescapeLineProtocol(k), value: unescapeLineProtocol(v)})
+	}
+
+	rest := strings.TrimLeft(line[identEnd+1:], " ")
+	fieldEnd := unescapedIndexByte(rest, ' ', true)
+	var fieldSet, tsToken string
+	if fieldEnd < 0 {
+		fieldSet = rest
+	} else {
+		fieldSet = rest[:fieldEnd]
+		tsToken = strings.TrimSpace(rest[fieldEnd+1:])
+	}
+	for _, tok := range splitUnescaped(fieldSet, ',', true) {
+		k, v, err := splitKV(tok)
+		if err != nil {
+			return "", nil, nil, nil, err
+		}
+		fields = append(fields, lpKV{key: unescapeLineProtocol(k), value: v})
+	}
+	if len(fields) == 0 {
+		return "", nil, nil, nil, fmt.Errorf("no fields found")
+	}
+
+	if tsToken != "" {
+		v, err := strconv.ParseInt(tsToken, 10, 64)
+		if err != nil {
+			return "", nil, nil, nil, fmt.Errorf("invalid timestamp %q: %w", tsToken, err)
+		}
+		ts = &v
+	}
+	return measurement, tags, fields, ts, nil
+}
+
+func splitKV(tok string) (key, value string, err error) {
+	i := unescapedIndexByte(tok, '=', false)
+	if i < 0 {
+		return "", "", fmt.Errorf("expected key=value, got %q", tok)
+	}
+	return tok[:i], tok[i+1:], nil
+}
+
+// unescapedIndexByte finds the first occurrence of b that isn't preceded by
+// an odd number of backslashes. When respectQuotes is true, occurrences
+// inside a double-quoted field value are skipped as well.
+func unescapedIndexByte(s string, b byte, respectQuotes bool) int {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case respectQuotes && c == '"':
+			inQuotes = !inQuotes
+		case c == b && !inQuotes:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitUnescaped splits s on sep, treating a backslash-escaped sep as a
+// literal character. When respectQuotes is true, sep occurrences inside a
+// double-quoted field value (the value half of a key=value token) are not
+// treated as separators either.
+func splitUnescaped(s string, sep byte, respectQuotes bool) []string {
+	var tokens []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case respectQuotes && c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			tokens = append(tokens, s[start:i])
+			start = i + 1
+		}
+	}
+	tokens = append(tokens, s[start:])
+	return tokens
+}
+
+// unescapeLineProtocol undoes backslash-escaping of commas, spaces and equals
+// signs in a measurement name, tag key, tag value or field key.
+func unescapeLineProtocol(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case ',', ' ', '=', '\\':
+				b.WriteByte(s[i+1])
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// decodeLineProtocolFieldValue decodes a raw field value token per its
+// line-protocol type suffix: a quoted string, an `i`-suffixed int64, a
+// `u`-suffixed uint64, a boolean literal, or a bare float64.
+func decodeLineProtocolFieldValue(raw string) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty field value")
+	}
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+	if raw[0] == '"' && raw[len(raw)-1] == '"' && len(raw) >= 2 {
+		return unescapeQuotedString(raw[1 : len(raw)-1]), nil
+	}
+	switch raw[len(raw)-1] {
+	case 'i':
+		return strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case 'u':
+		return strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+func unescapeQuotedString(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package timestream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestSplitLineProtocol(t *testing.T) {
+	cases := []struct {
+		name        string
+		line        string
+		measurement string
+		tags        []lpKV
+		fields      []lpKV
+		ts          *int64
+		wantErr     bool
+	}{
+		{
+			name:        "tags and fields with timestamp",
+			line:        "cpu,host=server01,region=us-west usage_user=64.2,usage_idle=30i 1600000000000000000",
+			measurement: "cpu",
+			tags: []lpKV{
+				{key: "host", value: "server01"},
+				{key: "region", value: "us-west"},
+			},
+			fields: []lpKV{
+				{key: "usage_user", value: "64.2"},
+				{key: "usage_idle", value: "30i"},
+			},
+			ts: int64Ptr(1600000000000000000),
+		},
+		{
+			name:        "no tags no timestamp",
+			line:        "cpu usage_user=1.0",
+			measurement: "cpu",
+			fields:      []lpKV{{key: "usage_user", value: "1.0"}},
+		},
+		{
+			name:        "escaped comma and space in tag value",
+			line:        `cpu,host=server\ 01,note=a\,b value=1i`,
+			measurement: "cpu",
+			tags: []lpKV{
+				{key: "host", value: "server 01"},
+				{key: "note", value: "a,b"},
+			},
+			fields: []lpKV{{key: "value", value: "1i"}},
+		},
+		{
+			name:        "quoted string field value with embedded comma and space",
+			line:        `event,host=a message="hello, world" 100`,
+			measurement: "event",
+			tags:        []lpKV{{key: "host", value: "a"}},
+			fields:      []lpKV{{key: "message", value: `"hello, world"`}},
+			ts:          int64Ptr(100),
+		},
+		{
+			name:    "missing field set",
+			line:    "cpu,host=server01",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			measurement, tags, fields, ts, err := splitLineProtocol(tc.line)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if measurement != tc.measurement {
+				t.Errorf("measurement = %q, want %q", measurement, tc.measurement)
+			}
+			if !reflect.DeepEqual(tags, tc.tags) {
+				t.Errorf("tags = %+v, want %+v", tags, tc.tags)
+			}
+			if !reflect.DeepEqual(fields, tc.fields) {
+				t.Errorf("fields = %+v, want %+v", fields, tc.fields)
+			}
+			if (ts == nil) != (tc.ts == nil) || (ts != nil && *ts != *tc.ts) {
+				t.Errorf("ts = %v, want %v", ts, tc.ts)
+			}
+		})
+	}
+}
+
+func TestUnescapeLineProtocol(t *testing.T) {
+	cases := map[string]string{
+		`server\ 01`:  "server 01",
+		`a\,b`:        "a,b",
+		`k\=v`:        "k=v",
+		"plain":       "plain",
+		`back\\slash`: `back\slash`,
+	}
+	for in, want := range cases {
+		if got := unescapeLineProtocol(in); got != want {
+			t.Errorf("unescapeLineProtocol(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDecodeLineProtocolFieldValue(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{raw: "30i", want: int64(30)},
+		{raw: "30u", want: uint64(30)},
+		{raw: "64.2", want: 64.2},
+		{raw: `"hello"`, want: "hello"},
+		{raw: `"with \"quotes\""`, want: `with "quotes"`},
+		{raw: "t", want: true},
+		{raw: "false", want: false},
+		{raw: "", wantErr: true},
+		{raw: "not-a-number", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := decodeLineProtocolFieldValue(tc.raw)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("decodeLineProtocolFieldValue(%q): expected error, got %v", tc.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("decodeLineProtocolFieldValue(%q): unexpected error: %v", tc.raw, err)
+		}
+		if got != tc.want {
+			t.Errorf("decodeLineProtocolFieldValue(%q) = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
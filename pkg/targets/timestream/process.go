@@ -0,0 +1,354 @@
+package timestream
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+// maxRecordsPerRequest and maxRequestSizeBytes are the hard limits Timestream
+// enforces on a single WriteRecords call; every processor in this target
+// must flush before either is exceeded.
+const (
+	maxRecordsPerRequest = 100
+	maxRequestSizeBytes  = 1 << 20
+)
+
+// eachValueARecordProcessor writes one Record per field value, the
+// straightforward mapping from a deserializedPoint to Timestream's model.
+type eachValueARecordProcessor struct {
+	dbName       string
+	batchPool    *sync.Pool
+	headers      *targets.DataSourceHeaders
+	writeService timestreamWriter
+	rotation     *RotationRouter
+}
+
+func (p *eachValueARecordProcessor) Init(_ int, _, _ bool) {}
+
+func (p *eachValueARecordProcessor) ProcessBatch(b targets.Batch, doLoad bool) (metricCount, rowCount uint64) {
+	bt := b.(*batch)
+
+	byTable := map[string][]*timestreamwrite.Record{}
+	var tableOrder []string
+	for _, point := range bt.points {
+		table := tableFor(p.rotation, point.timestamp)
+		if _, ok := byTable[table]; !ok {
+			tableOrder = append(tableOrder, table)
+		}
+
+		dimensions := dimensionsFor(p.headers.TagKeys, point.tags)
+		t := recordTime(point.timestamp)
+		for i, value := range point.fields {
+			if value == nil {
+				continue
+			}
+			valueType, strValue := measureValue(value)
+			byTable[table] = append(byTable[table], &timestreamwrite.Record{
+				Dimensions:       dimensions,
+				MeasureName:      aws.String(p.headers.FieldKeys[i]),
+				MeasureValue:     aws.String(strValue),
+				MeasureValueType: aws.String(valueType),
+				Time:             aws.String(t),
+				TimeUnit:         aws.String(timestreamwrite.TimeUnitMilliseconds),
+			})
+			metricCount++
+		}
+		rowCount++
+	}
+
+	if doLoad {
+		for _, table := range tableOrder {
+			for _, chunk := range chunkRecords(byTable[table]) {
+				if _, err := p.writeService.WriteRecords(&timestreamwrite.WriteRecordsInput{
+					DatabaseName: aws.String(p.dbName),
+					TableName:    aws.String(table),
+					Records:      chunk,
+				}); err != nil {
+					log.Fatalf("timestream eachValueARecordProcessor: write failed: %v", err)
+				}
+			}
+		}
+	}
+
+	bt.reset()
+	p.batchPool.Put(bt)
+	return metricCount, rowCount
+}
+
+// commonDimensionsProcessor groups records that share the same dimensions
+// under a single WriteRecordsInput.CommonAttributes, so repeated tag values
+// within a batch aren't re-sent on every record.
+type commonDimensionsProcessor struct {
+	dbName       string
+	batchPool    *sync.Pool
+	headers      *targets.DataSourceHeaders
+	writeService timestreamWriter
+	rotation     *RotationRouter
+}
+
+func (p *commonDimensionsProcessor) Init(_ int, _, _ bool) {}
+
+func (p *commonDimensionsProcessor) ProcessBatch(b targets.Batch, doLoad bool) (metricCount, rowCount uint64) {
+	bt := b.(*batch)
+
+	groups := map[string][]*timestreamwrite.Record{}
+	dimsByKey := map[string][]*timestreamwrite.Dimension{}
+	tableByKey := map[string]string{}
+	var order []string
+
+	for _, point := range bt.points {
+		table := tableFor(p.rotation, point.timestamp)
+		dimensions := dimensionsFor(p.headers.TagKeys, point.tags)
+		key := groupKey(table, dimensionKey(point.tags))
+		if _, ok := dimsByKey[key]; !ok {
+			dimsByKey[key] = dimensions
+			tableByKey[key] = table
+			order = append(order, key)
+		}
+		t := recordTime(point.timestamp)
+		for i, value := range point.fields {
+			if value == nil {
+				continue
+			}
+			valueType, strValue := measureValue(value)
+			groups[key] = append(groups[key], &timestreamwrite.Record{
+				MeasureName:      aws.String(p.headers.FieldKeys[i]),
+				MeasureValue:     aws.String(strValue),
+				MeasureValueType: aws.String(valueType),
+				Time:             aws.String(t),
+				TimeUnit:         aws.String(timestreamwrite.TimeUnitMilliseconds),
+			})
+			metricCount++
+		}
+		rowCount++
+	}
+
+	if doLoad {
+		for _, key := range order {
+			for _, chunk := range chunkRecords(groups[key]) {
+				_, err := p.writeService.WriteRecords(&timestreamwrite.WriteRecordsInput{
+					DatabaseName: aws.String(p.dbName),
+					TableName:    aws.String(tableByKey[key]),
+					CommonAttributes: &timestreamwrite.Record{
+						Dimensions: dimsByKey[key],
+					},
+					Records: chunk,
+				})
+				if err != nil {
+					log.Fatalf("timestream commonDimensionsProcessor: write failed: %v", err)
+				}
+			}
+		}
+	}
+
+	bt.reset()
+	p.batchPool.Put(bt)
+	return metricCount, rowCount
+}
+
+// multiMeasureProcessor packs every field of a point into a single MULTI
+// MeasureValueType Record instead of one Record per field, which cuts
+// payload size and per-record overhead roughly N-fold for wide points. When
+// useCommonAttributes is set, records that share the same dimensions also
+// share a single CommonAttributes.Dimensions instead of repeating them.
+type multiMeasureProcessor struct {
+	dbName              string
+	batchPool           *sync.Pool
+	headers             *targets.DataSourceHeaders
+	writeService        timestreamWriter
+	useCommonAttributes bool
+	rotation            *RotationRouter
+}
+
+// multiMeasureName is the MeasureName Timestream requires on every Record;
+// with MULTI records the individual field names live in MeasureValues
+// instead, so this is just a fixed label for the point as a whole.
+const multiMeasureName = "measure_group"
+
+func (p *multiMeasureProcessor) Init(_ int, _, _ bool) {}
+
+func (p *multiMeasureProcessor) ProcessBatch(b targets.Batch, doLoad bool) (metricCount, rowCount uint64) {
+	bt := b.(*batch)
+
+	groups := map[string][]*timestreamwrite.Record{}
+	dimsByKey := map[string][]*timestreamwrite.Dimension{}
+	tableByKey := map[string]string{}
+	var order []string
+
+	for _, point := range bt.points {
+		table := tableFor(p.rotation, point.timestamp)
+		dimensions := dimensionsFor(p.headers.TagKeys, point.tags)
+		measureValues := make([]*timestreamwrite.MeasureValue, 0, len(point.fields))
+		for i, value := range point.fields {
+			if value == nil {
+				continue
+			}
+			valueType, strValue := measureValue(value)
+			measureValues = append(measureValues, &timestreamwrite.MeasureValue{
+				Name:  aws.String(p.headers.FieldKeys[i]),
+				Value: aws.String(strValue),
+				Type:  aws.String(valueType),
+			})
+			metricCount++
+		}
+		rowCount++
+		if len(measureValues) == 0 {
+			continue
+		}
+
+		record := &timestreamwrite.Record{
+			MeasureName:      aws.String(multiMeasureName),
+			MeasureValueType: aws.String(timestreamwrite.MeasureValueTypeMulti),
+			MeasureValues:    measureValues,
+			Time:             aws.String(recordTime(point.timestamp)),
+			TimeUnit:         aws.String(timestreamwrite.TimeUnitMilliseconds),
+		}
+
+		// Without CommonAttributes every record carries its own Dimensions,
+		// so (unlike the useCommonAttributes branch) there's nothing forcing
+		// records to share a dimension set; group by table alone, same as
+		// eachValueARecordProcessor's byTable map, so a batch of points with
+		// all-distinct tag combinations still chunks up to the 100-record/
+		// 1MB limit instead of flushing one record at a time.
+		var key string
+		if p.useCommonAttributes {
+			key = groupKey(table, dimensionKey(point.tags))
+			if _, ok := dimsByKey[key]; !ok {
+				dimsByKey[key] = dimensions
+				tableByKey[key] = table
+				order = append(order, key)
+			}
+		} else {
+			record.Dimensions = dimensions
+			key = table
+			if _, ok := groups[key]; !ok {
+				tableByKey[key] = table
+				order = append(order, key)
+			}
+		}
+		groups[key] = append(groups[key], record)
+	}
+
+	if doLoad {
+		for _, key := range order {
+			for _, chunk := range chunkRecords(groups[key]) {
+				input := &timestreamwrite.WriteRecordsInput{
+					DatabaseName: aws.String(p.dbName),
+					TableName:    aws.String(tableByKey[key]),
+					Records:      chunk,
+				}
+				if p.useCommonAttributes {
+					input.CommonAttributes = &timestreamwrite.Record{Dimensions: dimsByKey[key]}
+				}
+				if _, err := p.writeService.WriteRecords(input); err != nil {
+					log.Fatalf("timestream multiMeasureProcessor: write failed: %v", err)
+				}
+			}
+		}
+	}
+
+	bt.reset()
+	p.batchPool.Put(bt)
+	return metricCount, rowCount
+}
+
+func dimensionsFor(tagKeys, tagValues []string) []*timestreamwrite.Dimension {
+	dimensions := make([]*timestreamwrite.Dimension, 0, len(tagKeys))
+	for i, key := range tagKeys {
+		if tagValues[i] == "" {
+			continue
+		}
+		dimensions = append(dimensions, &timestreamwrite.Dimension{
+			Name:  aws.String(key),
+			Value: aws.String(tagValues[i]),
+		})
+	}
+	return dimensions
+}
+
+func dimensionKey(tagValues []string) string {
+	return fmt.Sprintf("%v", tagValues)
+}
+
+// groupKey combines a table name and a dimension key so that, once rotation
+// is in play, records are never grouped together across tables even if
+// their dimensions happen to match.
+func groupKey(table, dimKey string) string {
+	return table + "\x00" + dimKey
+}
+
+// tableFor returns the table a point's timestamp should be written to: the
+// rotation ring's table when rotation is enabled, or the single tableName
+// otherwise.
+func tableFor(rotation *RotationRouter, timestampNs int64) string {
+	if rotation == nil {
+		return tableName
+	}
+	return rotation.TableFor(timestampNs)
+}
+
+// recordTime renders a point's timestamp, in nanoseconds since the epoch, as
+// the millisecond string Timestream's API expects.
+func recordTime(timestampNs int64) string {
+	return fmt.Sprintf("%d", timestampNs/1e6)
+}
+
+// measureValue renders a deserialized field value as the
+// (MeasureValueType, MeasureValue) pair WriteRecords expects.
+func measureValue(value interface{}) (valueType, strValue string) {
+	switch v := value.(type) {
+	case float64:
+		return timestreamwrite.MeasureValueTypeDouble, fmt.Sprintf("%v", v)
+	case int64:
+		return timestreamwrite.MeasureValueTypeBigint, fmt.Sprintf("%d", v)
+	case uint64:
+		return timestreamwrite.MeasureValueTypeBigint, fmt.Sprintf("%d", v)
+	case bool:
+		return timestreamwrite.MeasureValueTypeBoolean, fmt.Sprintf("%t", v)
+	case string:
+		return timestreamwrite.MeasureValueTypeVarchar, v
+	default:
+		return timestreamwrite.MeasureValueTypeVarchar, fmt.Sprintf("%v", v)
+	}
+}
+
+// chunkRecords splits records into Timestream-legal WriteRecords batches,
+// respecting both the per-request record count and payload size limits.
+func chunkRecords(records []*timestreamwrite.Record) [][]*timestreamwrite.Record {
+	var chunks [][]*timestreamwrite.Record
+	var current []*timestreamwrite.Record
+	currentSize := 0
+
+	for _, r := range records {
+		size := recordSize(r)
+		if len(current) >= maxRecordsPerRequest || (len(current) > 0 && currentSize+size > maxRequestSizeBytes) {
+			chunks = append(chunks, current)
+			current = nil
+			currentSize = 0
+		}
+		current = append(current, r)
+		currentSize += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// recordSize is a rough estimate of a Record's wire size, good enough to
+// stay comfortably under Timestream's 1MB per-request limit.
+func recordSize(r *timestreamwrite.Record) int {
+	size := len(aws.StringValue(r.MeasureName)) + len(aws.StringValue(r.MeasureValue)) + len(aws.StringValue(r.Time))
+	for _, d := range r.Dimensions {
+		size += len(aws.StringValue(d.Name)) + len(aws.StringValue(d.Value))
+	}
+	for _, mv := range r.MeasureValues {
+		size += len(aws.StringValue(mv.Name)) + len(aws.StringValue(mv.Value)) + len(aws.StringValue(mv.Type))
+	}
+	return size
+}
@@ -0,0 +1,157 @@
+package timestream
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+	"github.com/timescale/tsbs/pkg/targets"
+)
+
+func newTestPool() *sync.Pool {
+	return &sync.Pool{New: func() interface{} { return &batch{} }}
+}
+
+func pointsWithDistinctHosts(n int) []*deserializedPoint {
+	points := make([]*deserializedPoint, n)
+	for i := range points {
+		points[i] = &deserializedPoint{
+			measurementName: "cpu",
+			tags:            []string{fmt.Sprintf("host-%d", i)},
+			fields:          []interface{}{float64(i), int64(i)},
+			timestamp:       int64(i) * 1e6,
+		}
+	}
+	return points
+}
+
+func TestMultiMeasureProcessorPacksFieldsIntoSingleRecord(t *testing.T) {
+	writer := &fakeWriter{}
+	headers := &targets.DataSourceHeaders{TagKeys: []string{"hostname"}, FieldKeys: []string{"usage_user", "usage_idle"}}
+	p := &multiMeasureProcessor{
+		dbName:       "bench",
+		batchPool:    newTestPool(),
+		headers:      headers,
+		writeService: writer,
+	}
+
+	b := &batch{points: []*deserializedPoint{
+		{measurementName: "cpu", tags: []string{"host-1"}, fields: []interface{}{64.2, int64(30)}, timestamp: 100e6},
+	}}
+
+	metricCount, rowCount := p.ProcessBatch(b, true)
+	if metricCount != 2 {
+		t.Errorf("metricCount = %d, want 2", metricCount)
+	}
+	if rowCount != 1 {
+		t.Errorf("rowCount = %d, want 1", rowCount)
+	}
+	if len(writer.calls) != 1 {
+		t.Fatalf("expected 1 WriteRecords call, got %d", len(writer.calls))
+	}
+	records := writer.calls[0].Records
+	if len(records) != 1 {
+		t.Fatalf("expected 1 packed record, got %d", len(records))
+	}
+	if *records[0].MeasureValueType != timestreamwrite.MeasureValueTypeMulti {
+		t.Errorf("MeasureValueType = %q, want %q", *records[0].MeasureValueType, timestreamwrite.MeasureValueTypeMulti)
+	}
+	if len(records[0].MeasureValues) != 2 {
+		t.Errorf("MeasureValues length = %d, want 2", len(records[0].MeasureValues))
+	}
+}
+
+func TestMultiMeasureProcessorGroupsDistinctDimensionsByTableOnly(t *testing.T) {
+	writer := &fakeWriter{}
+	headers := &targets.DataSourceHeaders{TagKeys: []string{"hostname"}, FieldKeys: []string{"usage_user", "usage_idle"}}
+	p := &multiMeasureProcessor{
+		dbName:       "bench",
+		batchPool:    newTestPool(),
+		headers:      headers,
+		writeService: writer,
+	}
+
+	const n = 150
+	b := &batch{points: pointsWithDistinctHosts(n)}
+
+	_, rowCount := p.ProcessBatch(b, true)
+	if rowCount != n {
+		t.Errorf("rowCount = %d, want %d", rowCount, n)
+	}
+	// 150 points with all-distinct hostnames should still chunk by the
+	// 100-record limit (2 calls), not fan out to one call per distinct
+	// dimension set (150 calls).
+	if len(writer.calls) != 2 {
+		t.Fatalf("expected 2 WriteRecords calls (150 records chunked at 100), got %d", len(writer.calls))
+	}
+	total := 0
+	for _, call := range writer.calls {
+		if len(call.Records) > maxRecordsPerRequest {
+			t.Errorf("call has %d records, exceeds maxRecordsPerRequest %d", len(call.Records), maxRecordsPerRequest)
+		}
+		total += len(call.Records)
+	}
+	if total != n {
+		t.Errorf("total records written = %d, want %d", total, n)
+	}
+}
+
+func TestMultiMeasureProcessorCountsRowsWithAllNilFields(t *testing.T) {
+	writer := &fakeWriter{}
+	headers := &targets.DataSourceHeaders{TagKeys: []string{"hostname"}, FieldKeys: []string{"usage_user"}}
+	p := &multiMeasureProcessor{
+		dbName:       "bench",
+		batchPool:    newTestPool(),
+		headers:      headers,
+		writeService: writer,
+	}
+
+	b := &batch{points: []*deserializedPoint{
+		{measurementName: "cpu", tags: []string{"host-1"}, fields: []interface{}{nil}, timestamp: 100e6},
+		{measurementName: "cpu", tags: []string{"host-2"}, fields: []interface{}{1.0}, timestamp: 200e6},
+	}}
+
+	_, rowCount := p.ProcessBatch(b, true)
+	if rowCount != 2 {
+		t.Errorf("rowCount = %d, want 2 (including the all-nil-fields point)", rowCount)
+	}
+	// Only the second point produced a record; the first has nothing to write.
+	if len(writer.calls) != 1 || len(writer.calls[0].Records) != 1 {
+		t.Fatalf("expected 1 call with 1 record, got %+v", writer.calls)
+	}
+}
+
+func TestMultiMeasureProcessorUseCommonAttributes(t *testing.T) {
+	writer := &fakeWriter{}
+	headers := &targets.DataSourceHeaders{TagKeys: []string{"hostname"}, FieldKeys: []string{"usage_user"}}
+	p := &multiMeasureProcessor{
+		dbName:              "bench",
+		batchPool:           newTestPool(),
+		headers:             headers,
+		writeService:        writer,
+		useCommonAttributes: true,
+	}
+
+	b := &batch{points: []*deserializedPoint{
+		{measurementName: "cpu", tags: []string{"host-1"}, fields: []interface{}{1.0}, timestamp: 100e6},
+		{measurementName: "cpu", tags: []string{"host-1"}, fields: []interface{}{2.0}, timestamp: 200e6},
+		{measurementName: "cpu", tags: []string{"host-2"}, fields: []interface{}{3.0}, timestamp: 300e6},
+	}}
+
+	p.ProcessBatch(b, true)
+
+	if len(writer.calls) != 2 {
+		t.Fatalf("expected 1 call per distinct dimension set (2), got %d", len(writer.calls))
+	}
+	for _, call := range writer.calls {
+		if call.CommonAttributes == nil || len(call.CommonAttributes.Dimensions) == 0 {
+			t.Errorf("expected CommonAttributes.Dimensions to be set, got %+v", call.CommonAttributes)
+		}
+		for _, rec := range call.Records {
+			if len(rec.Dimensions) != 0 {
+				t.Errorf("expected individual records to omit Dimensions when useCommonAttributes is set, got %+v", rec.Dimensions)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package timestream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+)
+
+// timestreamWriter is the subset of *timestreamwrite.TimestreamWrite the
+// processors in this target need; retryingWriter implements it on top of a
+// real client so a batch-level failure doesn't have to abort the whole run.
+type timestreamWriter interface {
+	WriteRecords(*timestreamwrite.WriteRecordsInput) (*timestreamwrite.WriteRecordsOutput, error)
+}
+
+// retryingWriter wraps a Timestream client with retry/backoff for
+// throttling and transient server errors, automatic removal and resubmission
+// of individually-rejected records, and a dead-letter log for records that
+// still can't be written so a load run completes instead of aborting.
+type retryingWriter struct {
+	inner          timestreamWriter
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	deadLetter     *deadLetterWriter
+}
+
+func newRetryingWriter(inner timestreamWriter, config *SpecificConfig) (*retryingWriter, error) {
+	w := &retryingWriter{
+		inner:          inner,
+		maxRetries:     config.MaxRetries,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+	}
+	if w.maxRetries == 0 {
+		w.maxRetries = 5
+	}
+	if w.initialBackoff == 0 {
+		w.initialBackoff = 100 * time.Millisecond
+	}
+	if w.maxBackoff == 0 {
+		w.maxBackoff = 10 * time.Second
+	}
+	if config.DeadLetterPath != "" {
+		dlw, err := newDeadLetterWriter(config.DeadLetterPath)
+		if err != nil {
+			return nil, err
+		}
+		w.deadLetter = dlw
+	}
+	return w, nil
+}
+
+func (w *retryingWriter) WriteRecords(input *timestreamwrite.WriteRecordsInput) (*timestreamwrite.WriteRecordsOutput, error) {
+	var out *timestreamwrite.WriteRecordsOutput
+	for attempt := 0; ; attempt++ {
+		var err error
+		out, err = w.inner.WriteRecords(input)
+		if err == nil {
+			return out, nil
+		}
+
+		if rejected, ok := err.(*timestreamwrite.RejectedRecordsException); ok {
+			survivors := w.handleRejectedRecords(input, rejected)
+			if len(survivors) == 0 {
+				return out, nil
+			}
+			input = &timestreamwrite.WriteRecordsInput{
+				DatabaseName:     input.DatabaseName,
+				TableName:        input.TableName,
+				CommonAttributes: input.CommonAttributes,
+				Records:          survivors,
+			}
+			continue
+		}
+
+		if !isRetryable(err) || attempt >= w.maxRetries {
+			if w.deadLetter != nil {
+				w.deadLetterAll(input, err)
+				return out, nil
+			}
+			return out, err
+		}
+
+		time.Sleep(backoffWithJitter(w.initialBackoff, w.maxBackoff, attempt))
+	}
+}
+
+// handleRejectedRecords removes the indices AWS reported as rejected,
+// dead-lettering each one along with its reason code, and returns the
+// remaining records to resubmit.
+func (w *retryingWriter) handleRejectedRecords(input *timestreamwrite.WriteRecordsInput, rejected *timestreamwrite.RejectedRecordsException) []*timestreamwrite.Record {
+	bad := make(map[int64]string, len(rejected.RejectedRecords))
+	for _, r := range rejected.RejectedRecords {
+		reason := aws.StringValue(r.Reason)
+		bad[aws.Int64Value(r.Index)] = reason
+		if w.deadLetter != nil {
+			w.deadLetter.Write(input, input.Records[aws.Int64Value(r.Index)], reason)
+		}
+	}
+
+	survivors := make([]*timestreamwrite.Record, 0, len(input.Records)-len(bad))
+	for i, rec := range input.Records {
+		if _, rejected := bad[int64(i)]; !rejected {
+			survivors = append(survivors, rec)
+		}
+	}
+	return survivors
+}
+
+func (w *retryingWriter) deadLetterAll(input *timestreamwrite.WriteRecordsInput, err error) {
+	reason := err.Error()
+	for _, rec := range input.Records {
+		w.deadLetter.Write(input, rec, reason)
+	}
+}
+
+// isRetryable reports whether err is a throttling or transient server error
+// worth backing off and retrying, as opposed to a permanent rejection.
+func isRetryable(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case timestreamwrite.ErrCodeThrottlingException,
+		timestreamwrite.ErrCodeInternalServerException,
+		request.ErrCodeSerialization:
+		return true
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() >= 500
+	}
+	return false
+}
+
+// backoffWithJitter returns an exponential backoff duration, capped at max,
+// with full jitter so retrying workers don't all hammer Timestream in lockstep.
+func backoffWithJitter(initial, max time.Duration, attempt int) time.Duration {
+	d := initial << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// deadLetterWriter appends records TSBS could not write to Timestream to a
+// file as line-delimited JSON, so operators can inspect or replay them
+// after the run completes.
+type deadLetterWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func newDeadLetterWriter(path string) (*deadLetterWriter, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open dead-letter file %q: %w", path, err)
+	}
+	return &deadLetterWriter{f: f}, nil
+}
+
+type deadLetterEntry struct {
+	Database         string            `json:"database"`
+	Table            string            `json:"table"`
+	MeasureName      string            `json:"measure_name"`
+	MeasureValue     string            `json:"measure_value,omitempty"`
+	MeasureValueType string            `json:"measure_value_type,omitempty"`
+	MeasureValues    []deadLetterValue `json:"measure_values,omitempty"`
+	Dimensions       map[string]string `json:"dimensions,omitempty"`
+	Time             string            `json:"time"`
+	Reason           string            `json:"reason"`
+}
+
+// deadLetterValue mirrors a timestreamwrite.MeasureValue entry, for MULTI
+// records written by multiMeasureProcessor.
+type deadLetterValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+func (w *deadLetterWriter) Write(input *timestreamwrite.WriteRecordsInput, rec *timestreamwrite.Record, reason string) {
+	dims := map[string]string{}
+	for _, d := range mergedDimensions(input, rec) {
+		dims[aws.StringValue(d.Name)] = aws.StringValue(d.Value)
+	}
+	entry := deadLetterEntry{
+		Database:         aws.StringValue(input.DatabaseName),
+		Table:            aws.StringValue(input.TableName),
+		MeasureName:      aws.StringValue(rec.MeasureName),
+		MeasureValue:     aws.StringValue(rec.MeasureValue),
+		MeasureValueType: aws.StringValue(rec.MeasureValueType),
+		Dimensions:       dims,
+		Time:             aws.StringValue(rec.Time),
+		Reason:           reason,
+	}
+	for _, mv := range rec.MeasureValues {
+		entry.MeasureValues = append(entry.MeasureValues, deadLetterValue{
+			Name:  aws.StringValue(mv.Name),
+			Value: aws.StringValue(mv.Value),
+			Type:  aws.StringValue(mv.Type),
+		})
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("timestream dead-letter: could not marshal record: %v", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.f.Write(append(line, '\n')); err != nil {
+		log.Printf("timestream dead-letter: could not write record: %v", err)
+	}
+}
+
+func mergedDimensions(input *timestreamwrite.WriteRecordsInput, rec *timestreamwrite.Record) []*timestreamwrite.Dimension {
+	if len(rec.Dimensions) > 0 {
+		return rec.Dimensions
+	}
+	if input.CommonAttributes != nil {
+		return input.CommonAttributes.Dimensions
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package timestream
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+)
+
+// fakeWriter is a scripted timestreamWriter: each call to WriteRecords pops
+// the next (output, error) pair queued for it and records the input it saw.
+type fakeWriter struct {
+	responses []fakeResponse
+	calls     []*timestreamwrite.WriteRecordsInput
+}
+
+type fakeResponse struct {
+	out *timestreamwrite.WriteRecordsOutput
+	err error
+}
+
+func (f *fakeWriter) WriteRecords(input *timestreamwrite.WriteRecordsInput) (*timestreamwrite.WriteRecordsOutput, error) {
+	f.calls = append(f.calls, input)
+	if len(f.responses) == 0 {
+		return &timestreamwrite.WriteRecordsOutput{}, nil
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	if resp.out == nil {
+		resp.out = &timestreamwrite.WriteRecordsOutput{}
+	}
+	return resp.out, resp.err
+}
+
+func testRecords(n int) []*timestreamwrite.Record {
+	records := make([]*timestreamwrite.Record, n)
+	for i := range records {
+		records[i] = &timestreamwrite.Record{
+			MeasureName:      aws.String("usage_user"),
+			MeasureValue:     aws.String("1.0"),
+			MeasureValueType: aws.String(timestreamwrite.MeasureValueTypeDouble),
+			Time:             aws.String("100"),
+		}
+	}
+	return records
+}
+
+func TestRetryingWriterWriteRecordsSuccess(t *testing.T) {
+	inner := &fakeWriter{}
+	w, err := newRetryingWriter(inner, &SpecificConfig{})
+	if err != nil {
+		t.Fatalf("newRetryingWriter: %v", err)
+	}
+
+	input := &timestreamwrite.WriteRecordsInput{Records: testRecords(2)}
+	if _, err := w.WriteRecords(input); err != nil {
+		t.Fatalf("WriteRecords: unexpected error: %v", err)
+	}
+	if len(inner.calls) != 1 {
+		t.Fatalf("expected exactly 1 call to inner writer, got %d", len(inner.calls))
+	}
+}
+
+func TestRetryingWriterWriteRecordsRetriesTransientError(t *testing.T) {
+	throttled := awserr.New(timestreamwrite.ErrCodeThrottlingException, "slow down", nil)
+	inner := &fakeWriter{
+		responses: []fakeResponse{
+			{err: throttled},
+			{err: throttled},
+		},
+	}
+	w, err := newRetryingWriter(inner, &SpecificConfig{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("newRetryingWriter: %v", err)
+	}
+
+	input := &timestreamwrite.WriteRecordsInput{Records: testRecords(1)}
+	if _, err := w.WriteRecords(input); err != nil {
+		t.Fatalf("WriteRecords: unexpected error: %v", err)
+	}
+	if len(inner.calls) != 3 {
+		t.Fatalf("expected 3 calls (2 retries + success), got %d", len(inner.calls))
+	}
+}
+
+func TestRetryingWriterWriteRecordsGivesUpOnNonRetryableError(t *testing.T) {
+	permanent := awserr.New("ValidationException", "bad request", nil)
+	inner := &fakeWriter{responses: []fakeResponse{{err: permanent}}}
+	w, err := newRetryingWriter(inner, &SpecificConfig{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("newRetryingWriter: %v", err)
+	}
+
+	input := &timestreamwrite.WriteRecordsInput{Records: testRecords(1)}
+	if _, err := w.WriteRecords(input); err != permanent {
+		t.Fatalf("expected the non-retryable error to surface unchanged, got %v", err)
+	}
+	if len(inner.calls) != 1 {
+		t.Fatalf("expected no retries for a non-retryable error, got %d calls", len(inner.calls))
+	}
+}
+
+func TestRetryingWriterWriteRecordsResubmitsAfterRejection(t *testing.T) {
+	rejected := &timestreamwrite.RejectedRecordsException{
+		RejectedRecords: []*timestreamwrite.RejectedRecord{
+			{Index: aws.Int64(1), Reason: aws.String("value outside retention window")},
+		},
+	}
+	inner := &fakeWriter{responses: []fakeResponse{{err: rejected}}}
+	w, err := newRetryingWriter(inner, &SpecificConfig{})
+	if err != nil {
+		t.Fatalf("newRetryingWriter: %v", err)
+	}
+
+	input := &timestreamwrite.WriteRecordsInput{Records: testRecords(2)}
+	if _, err := w.WriteRecords(input); err != nil {
+		t.Fatalf("WriteRecords: unexpected error: %v", err)
+	}
+	if len(inner.calls) != 2 {
+		t.Fatalf("expected the survivor to be resubmitted in a second call, got %d calls", len(inner.calls))
+	}
+	if got := len(inner.calls[1].Records); got != 1 {
+		t.Fatalf("expected 1 surviving record resubmitted, got %d", got)
+	}
+}
+
+func TestRetryingWriterDeadLettersOnExhaustedRetries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dead-letter.jsonl")
+
+	permanent := awserr.New("ValidationException", "bad request", nil)
+	inner := &fakeWriter{responses: []fakeResponse{{err: permanent}}}
+	w, err := newRetryingWriter(inner, &SpecificConfig{DeadLetterPath: path})
+	if err != nil {
+		t.Fatalf("newRetryingWriter: %v", err)
+	}
+
+	input := &timestreamwrite.WriteRecordsInput{
+		DatabaseName: aws.String("bench"),
+		TableName:    aws.String("measurements"),
+		Records:      testRecords(1),
+	}
+	if _, err := w.WriteRecords(input); err != nil {
+		t.Fatalf("expected dead-lettering to swallow the error, got %v", err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading dead-letter file: %v", err)
+	}
+	if len(contents) == 0 {
+		t.Fatal("expected a dead-letter entry to be written, file is empty")
+	}
+}
+
+func TestHandleRejectedRecords(t *testing.T) {
+	w := &retryingWriter{}
+	input := &timestreamwrite.WriteRecordsInput{Records: testRecords(3)}
+	rejected := &timestreamwrite.RejectedRecordsException{
+		RejectedRecords: []*timestreamwrite.RejectedRecord{
+			{Index: aws.Int64(0), Reason: aws.String("duplicate")},
+			{Index: aws.Int64(2), Reason: aws.String("out of range")},
+		},
+	}
+
+	survivors := w.handleRejectedRecords(input, rejected)
+	if len(survivors) != 1 {
+		t.Fatalf("expected 1 survivor, got %d", len(survivors))
+	}
+	if survivors[0] != input.Records[1] {
+		t.Fatal("expected the untouched middle record to survive")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttling", awserr.New(timestreamwrite.ErrCodeThrottlingException, "slow down", nil), true},
+		{"internal server", awserr.New(timestreamwrite.ErrCodeInternalServerException, "oops", nil), true},
+		{"validation", awserr.New("ValidationException", "bad request", nil), false},
+		{"plain error", os.ErrClosed, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package timestream
+
+import (
+	"fmt"
+	"time"
+)
+
+// RotationRouter maps a point's timestamp to one of a fixed ring of N tables
+// instead of the single table this target otherwise writes to, borrowing the
+// segment/shard rotation model used by tiered TSDBs: each table holds one
+// time window's worth of points, and writes to distinct windows fewer than N
+// periods apart never contend for the same table. Windows a multiple of N
+// periods apart map to the same table.
+type RotationRouter struct {
+	baseTableName string
+	period        time.Duration
+	count         int
+}
+
+// rotationPeriods maps the --rotation flag values this target accepts to
+// the window width each one rotates tables on.
+var rotationPeriods = map[string]time.Duration{
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// NewRotationRouter builds the router for a --rotation=daily|weekly|monthly
+// policy with a ring of rotationCount tables.
+func NewRotationRouter(baseTableName, rotation string, rotationCount int) (*RotationRouter, error) {
+	period, ok := rotationPeriods[rotation]
+	if !ok {
+		return nil, fmt.Errorf("unknown rotation period %q", rotation)
+	}
+	if rotationCount < 1 {
+		return nil, fmt.Errorf("rotation-count must be at least 1, got %d", rotationCount)
+	}
+	return &RotationRouter{baseTableName: baseTableName, period: period, count: rotationCount}, nil
+}
+
+// TableNames returns the full ring of table names this router routes to, in
+// segment order, for dbCreator to pre-create up front.
+func (r *RotationRouter) TableNames() []string {
+	names := make([]string, r.count)
+	for i := range names {
+		names[i] = r.tableForSegment(i)
+	}
+	return names
+}
+
+// TableFor returns the table the point at timestampNs (nanoseconds since the
+// epoch) should be written to.
+func (r *RotationRouter) TableFor(timestampNs int64) string {
+	return r.tableForSegment(r.SegmentFor(timestampNs))
+}
+
+// SegmentFor returns the ring position for timestampNs, for use as an extra
+// partitioning dimension so writer goroutines don't contend across windows.
+func (r *RotationRouter) SegmentFor(timestampNs int64) int {
+	windowIndex := timestampNs / int64(r.period)
+	return int(((windowIndex % int64(r.count)) + int64(r.count)) % int64(r.count))
+}
+
+// segmentAge returns how many windows old segment currently is relative to
+// now, where 0 is the current window and larger is older; used to scale
+// down memory-store retention for segments that hold older data.
+func (r *RotationRouter) segmentAge(segment int, now time.Time) int {
+	current := r.SegmentFor(now.UnixNano())
+	return int((int64(current-segment) + int64(r.count)) % int64(r.count))
+}
+
+func (r *RotationRouter) tableForSegment(segment int) string {
+	return fmt.Sprintf("%s_%d", r.baseTableName, segment)
+}